@@ -1,16 +1,52 @@
 package schema
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"akvorado/common/helpers"
 	"akvorado/common/s3"
 )
 
+// DictSource is the interface a dictionary source should implement. It
+// abstracts fetching dictionary files, skipping a reload when the remote
+// content has not changed since the last fetch (GetIfChanged), and
+// discovering dictionaries by prefix instead of hard-coding one key per dict.
+//
+// Get's signature gained a context.Context argument compared to the baseline
+// (needed to carry FetchConfiguration's timeout through retries), which is a
+// breaking change for any caller outside this file. Wiring GetIfChanged into
+// the ClickHouse dict-refresh loop, and updating that loop's call to Get
+// accordingly, is a pending follow-up: no caller of this interface exists yet
+// in this tree.
 type DictSource interface {
-	Get(key string) (io.ReadCloser, error)
+	// Get returns a reader for the content of key. It is a convenience
+	// wrapper around GetIfChanged with no previous ETag.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetIfChanged returns a reader for the content of key, unless it has
+	// not changed since prevETag was obtained, in which case notModified is
+	// true and rc is nil. newETag identifies the fetched version of key (or
+	// the still-current one when notModified is true) and should be passed
+	// back as prevETag on the next call.
+	GetIfChanged(ctx context.Context, key string, prevETag string) (rc io.ReadCloser, newETag string, notModified bool, err error)
+	// List returns the keys available under prefix, so that a set of
+	// dictionaries (e.g. every "asn/*.csv") can be discovered instead of
+	// being listed one by one in the configuration.
+	List(prefix string) ([]string, error)
 }
 
 // DictSourceConfiguration represents the configuration of a cache backend.
@@ -18,106 +54,562 @@ type DictSourceConfiguration interface {
 	New(c *s3.Component) (DictSource, error)
 }
 
+// FetchConfiguration holds the retry, timeout and checksum-verification
+// options shared by all dict source backends.
+type FetchConfiguration struct {
+	// MaxRetries is the number of times a failed fetch is retried before
+	// giving up. 0 disables retries.
+	MaxRetries int `validate:"min=0"`
+	// RetryInitialBackoff is the delay before the first retry. It is
+	// doubled after each subsequent attempt.
+	RetryInitialBackoff time.Duration `validate:"min=0"`
+	// Timeout bounds how long a fetch, including retries and reading the
+	// returned content until it is closed, may take. 0 means no timeout.
+	Timeout time.Duration `validate:"min=0"`
+	// VerifyChecksum, when true, also fetches a sidecar "<key>.sha256" file
+	// from the same source and rejects the result when it does not match.
+	VerifyChecksum bool
+}
+
+// DefaultFetchConfiguration returns the default fetch configuration shared
+// by all dict source backends.
+func DefaultFetchConfiguration() FetchConfiguration {
+	return FetchConfiguration{
+		MaxRetries:          2,
+		RetryInitialBackoff: 200 * time.Millisecond,
+		Timeout:             30 * time.Second,
+	}
+}
+
+// rawDictSource is implemented by each backend. It only knows how to fetch
+// and list keys once; retries, timeouts and checksum verification are
+// handled by wrappedDictSource.
+type rawDictSource interface {
+	getIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error)
+	list(prefix string) ([]string, error)
+}
+
+// wrappedDictSource adds retry-with-backoff, a per-request timeout and
+// optional checksum verification on top of a rawDictSource.
+type wrappedDictSource struct {
+	raw    rawDictSource
+	config FetchConfiguration
+}
+
+// Get returns a file reader for the specified key.
+func (w wrappedDictSource) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, _, _, err := w.GetIfChanged(ctx, key, "")
+	return rc, err
+}
+
+// GetIfChanged fetches key, retrying on failure and verifying its checksum
+// when configured to do so.
+func (w wrappedDictSource) GetIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error) {
+	cancel := context.CancelFunc(func() {})
+	if w.config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, w.config.Timeout)
+	}
+	// The context must outlive this call when a reader is returned, as
+	// cancelling it aborts an HTTP response body still being read.
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			cancel()
+		}
+	}()
+
+	var rc io.ReadCloser
+	var etag string
+	var notModified bool
+	var err error
+	backoff := w.config.RetryInitialBackoff
+	for attempt := 0; ; attempt++ {
+		rc, etag, notModified, err = w.raw.getIfChanged(ctx, key, prevETag)
+		if err == nil || attempt >= w.config.MaxRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, "", false, fmt.Errorf("fetching %q: %w", key, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		return nil, "", false, fmt.Errorf("fetching %q: %w", key, err)
+	}
+	if notModified {
+		return nil, etag, true, nil
+	}
+	if w.config.VerifyChecksum {
+		rc, err = w.verifyChecksum(ctx, key, rc)
+		if err != nil {
+			return nil, "", false, err
+		}
+	}
+	handedOff = true
+	return cancelOnClose{ReadCloser: rc, cancel: cancel}, etag, false, nil
+}
+
+// cancelOnClose releases the fetch context once the returned content is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// List returns the keys available under prefix.
+func (w wrappedDictSource) List(prefix string) ([]string, error) {
+	return w.raw.list(prefix)
+}
+
+// verifyChecksum reads content fully, fetches the "<key>.sha256" sidecar and
+// compares it against the SHA-256 sum of content, returning a fresh reader
+// over the same bytes on success.
+func (w wrappedDictSource) verifyChecksum(ctx context.Context, key string, content io.ReadCloser) (io.ReadCloser, error) {
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %w", key, err)
+	}
+	sidecar, _, _, err := w.raw.getIfChanged(ctx, key+".sha256", "")
+	if err != nil {
+		return nil, fmt.Errorf("fetching checksum for %q: %w", key, err)
+	}
+	defer sidecar.Close()
+	wantRaw, err := io.ReadAll(sidecar)
+	if err != nil {
+		return nil, fmt.Errorf("reading checksum for %q: %w", key, err)
+	}
+	fields := strings.Fields(string(wantRaw))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty checksum file for %q", key)
+	}
+	got := sha256.Sum256(data)
+	if !strings.EqualFold(fields[0], hex.EncodeToString(got[:])) {
+		return nil, fmt.Errorf("checksum mismatch for %q", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 // FileDictSourceConfiguration is the configuration for a dict source reading
-// from the local filesystem. There is no configuration.
-type FileDictSourceConfiguration struct{}
+// from the local filesystem.
+type FileDictSourceConfiguration struct {
+	Fetch FetchConfiguration
+}
 
 // New creates a new file dict source from a file dict source configuration.
-func (FileDictSourceConfiguration) New(c *s3.Component) (DictSource, error) {
-	return FileDictSource{}, nil
+func (c FileDictSourceConfiguration) New(s3c *s3.Component) (DictSource, error) {
+	return wrappedDictSource{raw: FileDictSource{}, config: c.Fetch}, nil
 }
 
-// FileDictSource is a dict source reading from the local filesystem.
+// FileDictSource is a dict source reading from the local filesystem. Change
+// detection is based on the file's modification time and size.
 type FileDictSource struct{}
 
-// Get returns a file reader for the specified filename.
-func (FileDictSource) Get(key string) (io.ReadCloser, error) {
-	return os.Open(key)
+func (FileDictSource) getIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error) {
+	fi, err := os.Stat(key)
+	if err != nil {
+		return nil, "", false, err
+	}
+	etag := fmt.Sprintf("%d-%d", fi.ModTime().UnixNano(), fi.Size())
+	if prevETag != "" && prevETag == etag {
+		return nil, etag, true, nil
+	}
+	f, err := os.Open(key)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return f, etag, false, nil
+}
+
+// list returns every regular file whose path starts with prefix, by
+// walking the containing directory.
+func (FileDictSource) list(prefix string) ([]string, error) {
+	dir := filepath.Dir(prefix)
+	var keys []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(path, prefix) {
+			keys = append(keys, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
 }
 
 // DefaultFileDictSourceConfiguration returns the default configuration for a
 // filesystem based dict source.
 func DefaultFileDictSourceConfiguration() DictSourceConfiguration {
-	return FileDictSourceConfiguration{}
+	return FileDictSourceConfiguration{Fetch: DefaultFetchConfiguration()}
 }
 
 // HttpDictSourceConfiguration is the configuration for a dict source reading
 // from the specified HTTP endpoint.
 type HttpDictSourceConfiguration struct {
 	BaseURL string
+	Fetch   FetchConfiguration
 }
 
 // New creates a new HTTP dict source from a HTTP dict source configuration.
-func (HttpDictSourceConfiguration) New(c *s3.Component) (DictSource, error) {
-	return HttpDictSource{}, nil
+// Keys that turn out to be S3 URLs (see ParseS3URL) are transparently routed
+// through the authenticated common/s3 client when s3c has credentials
+// configured for their bucket, instead of being fetched anonymously.
+func (c HttpDictSourceConfiguration) New(s3c *s3.Component) (DictSource, error) {
+	return wrappedDictSource{raw: HttpDictSource{s3c: s3c}, config: c.Fetch}, nil
 }
 
+// httpETagPrefix and httpLastModifiedPrefix tag the opaque ETag string
+// HttpDictSource hands back to wrappedDictSource, so that on the next call
+// it knows whether to send it back as If-None-Match or as
+// If-Modified-Since: the two are not interchangeable, and sending an ETag
+// value as If-Modified-Since produces a malformed header.
+const (
+	httpETagPrefix         = "etag:"
+	httpLastModifiedPrefix = "lastmod:"
+)
+
 // HttpDictSource is a dict source reading from the specified HTTP endpoint.
-type HttpDictSource struct{}
+// It sends If-None-Match or If-Modified-Since (whichever matches what the
+// server returned last time) on subsequent fetches, so the server can reply
+// with a 304 when the resource has not changed.
+type HttpDictSource struct {
+	// s3c, when set, is used to fetch keys that parse as S3 URLs through an
+	// authenticated client instead of anonymous HTTP, provided it has
+	// credentials configured for their bucket.
+	s3c *s3.Component
+}
 
-// Get returns a file reader for the specified URL.
-func (HttpDictSource) Get(key string) (io.ReadCloser, error) {
-	resp, err := http.Get(key)
+// s3Backend returns the S3DictSource to use for key, when key is an S3 URL
+// (see ParseS3URL) and s3c has a configured client for its bucket.
+func (h HttpDictSource) s3Backend(key string) (S3DictSource, string, bool) {
+	parsed, ok := ParseS3URL(key)
+	if !ok || h.s3c == nil {
+		return S3DictSource{}, "", false
+	}
+	configName, found := h.s3c.LookupBucketConfig(parsed.Bucket)
+	if !found {
+		return S3DictSource{}, "", false
+	}
+	return S3DictSource{config: S3DictSourceConfiguration{S3Config: configName}, c: h.s3c}, parsed.Key, true
+}
+
+func (h HttpDictSource) getIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error) {
+	if backend, objectKey, ok := h.s3Backend(key); ok {
+		return backend.getIfChanged(ctx, objectKey, prevETag)
+	}
+	if parsed, ok := ParseS3URL(key); ok && strings.HasPrefix(key, "s3://") {
+		key = parsed.anonymousURL()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
+	}
+	switch {
+	case strings.HasPrefix(prevETag, httpETagPrefix):
+		req.Header.Set("If-None-Match", strings.TrimPrefix(prevETag, httpETagPrefix))
+	case strings.HasPrefix(prevETag, httpLastModifiedPrefix):
+		req.Header.Set("If-Modified-Since", strings.TrimPrefix(prevETag, httpLastModifiedPrefix))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, prevETag, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", false, fmt.Errorf("unexpected status fetching %q: %s", key, resp.Status)
+	}
+	var newTag string
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		newTag = httpETagPrefix + etag
+	} else if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		newTag = httpLastModifiedPrefix + lm
+	}
+	return resp.Body, newTag, false, nil
+}
+
+// list fetches prefix as a manifest file containing one key per line. This
+// lets a plain HTTP server expose prefix-based discovery without a real
+// directory index. When prefix is an S3 URL routed through an authenticated
+// client, it lists the bucket instead.
+func (h HttpDictSource) list(prefix string) ([]string, error) {
+	if backend, objectPrefix, ok := h.s3Backend(prefix); ok {
+		return backend.list(objectPrefix)
+	}
+	if parsed, ok := ParseS3URL(prefix); ok && strings.HasPrefix(prefix, "s3://") {
+		prefix = parsed.anonymousURL()
 	}
 
-	return resp.Body, nil
+	resp, err := http.Get(prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status listing %q: %s", prefix, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys, nil
 }
 
 // DefaultHttpDictSourceConfiguration returns the default configuration for a
 // HTTP dict source configuration.
 func DefaultHttpDictSourceConfiguration() DictSourceConfiguration {
-	return HttpDictSourceConfiguration{}
+	return HttpDictSourceConfiguration{Fetch: DefaultFetchConfiguration()}
+}
+
+// ParsedS3URL holds the bucket, region and key extracted from an S3 URL.
+type ParsedS3URL struct {
+	Bucket string
+	Region string
+	Key    string
+}
+
+var (
+	s3VirtualHostURLRegexp = regexp.MustCompile(`^https?://([^./]+)\.s3(?:[.-]([a-z0-9-]+))?\.amazonaws\.com/(.+)$`)
+	s3PathStyleURLRegexp   = regexp.MustCompile(`^https?://s3(?:[.-]([a-z0-9-]+))?\.amazonaws\.com/([^/]+)/(.+)$`)
+)
+
+// ParseS3URL recognises a virtual-hosted-style URL
+// (https://<bucket>.s3[.-]<region>.amazonaws.com/<key>), a path-style one
+// (https://s3[.-]<region>.amazonaws.com/<bucket>/<key>) or an s3://<bucket>/<key>
+// one, and extracts the bucket, region (empty for s3://, since it does not
+// carry one) and key from it. ok is false when rawURL matches none of these.
+func ParseS3URL(rawURL string) (parsed ParsedS3URL, ok bool) {
+	if strings.HasPrefix(rawURL, "s3://") {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" || len(u.Path) < 2 {
+			return ParsedS3URL{}, false
+		}
+		return ParsedS3URL{Bucket: u.Host, Key: strings.TrimPrefix(u.Path, "/")}, true
+	}
+	if m := s3VirtualHostURLRegexp.FindStringSubmatch(rawURL); m != nil {
+		return ParsedS3URL{Bucket: m[1], Region: m[2], Key: m[3]}, true
+	}
+	if m := s3PathStyleURLRegexp.FindStringSubmatch(rawURL); m != nil {
+		return ParsedS3URL{Bucket: m[2], Region: m[1], Key: m[3]}, true
+	}
+	return ParsedS3URL{}, false
+}
+
+// anonymousURL reconstructs a plain HTTPS URL for parsed, for use when no
+// authenticated S3 client is available for its bucket.
+func (p ParsedS3URL) anonymousURL() string {
+	if p.Region == "" {
+		return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", p.Bucket, p.Key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", p.Bucket, p.Region, p.Key)
 }
 
 // S3DictSourceConfiguration is the configuration for a dict source reading
 // from the specified S3 config.
 type S3DictSourceConfiguration struct {
 	S3Config string
+	Fetch    FetchConfiguration
 }
 
 // New creates a new S3 dict source from an S3 dict source configuration.
-func (sc S3DictSourceConfiguration) New(c *s3.Component) (DictSource, error) {
-	return S3DictSource{config: sc, c: c}, nil
+func (c S3DictSourceConfiguration) New(s3c *s3.Component) (DictSource, error) {
+	return wrappedDictSource{raw: S3DictSource{config: c, c: s3c}, config: c.Fetch}, nil
 }
 
-// S3DictSource is a dict source reading from the specified S3 bucket.
+// S3DictSource is a dict source reading from the specified S3 bucket. Change
+// detection relies on the object's ETag as returned by HeadObject.
 type S3DictSource struct {
 	config S3DictSourceConfiguration
 	c      *s3.Component
 }
 
-// Get returns a file reader for the specified S3 object.
-func (s S3DictSource) Get(key string) (io.ReadCloser, error) {
-	read, err := s.c.GetObject(s.config.S3Config, key)
+func (s S3DictSource) getIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error) {
+	etag, _, err := s.c.HeadObject(s.config.S3Config, key)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
+	}
+	if prevETag != "" && prevETag == etag {
+		return nil, etag, true, nil
+	}
+	rc, err := s.c.GetObject(s.config.S3Config, key)
+	if err != nil {
+		return nil, "", false, err
 	}
+	return rc, etag, false, nil
+}
 
-	return read, nil
+func (s S3DictSource) list(prefix string) ([]string, error) {
+	return s.c.ListObjects(s.config.S3Config, prefix)
 }
 
 // DefaultS3DictSourceConfiguration returns the default configuration for a
 // S3 dict source.
 func DefaultS3DictSourceConfiguration() DictSourceConfiguration {
-	return S3DictSourceConfiguration{}
+	return S3DictSourceConfiguration{Fetch: DefaultFetchConfiguration()}
 }
 
 // S3MockDictSourceConfiguration is the configuration for a dict source reading
 // from a mocked S3 endpoint.
 type S3MockDictSourceConfiguration struct {
+	Fetch FetchConfiguration
 }
 
-// New creates a new S3 dict source from an S3 dict source configuration.
-func (sc S3MockDictSourceConfiguration) New(c *s3.Component) (DictSource, error) {
-	panic("not implemented")
+// New creates a new dict source backed by a fresh, empty in-process S3
+// mock. Tests that need to seed objects should use NewS3Mock() and
+// NewS3MockDictSource() directly instead, so they keep a handle on the mock
+// to call Seed().
+func (c S3MockDictSourceConfiguration) New(s3c *s3.Component) (DictSource, error) {
+	return NewS3MockDictSource(NewS3Mock(), c.Fetch), nil
 }
 
 // DefaultS3MockDictSourceConfiguration returns the default configuration for a
 // mocked S3 dict source.
 func DefaultS3MockDictSourceConfiguration() DictSourceConfiguration {
-	return S3MockDictSourceConfiguration{}
+	return S3MockDictSourceConfiguration{Fetch: DefaultFetchConfiguration()}
+}
+
+// S3Mock is an in-process mock of a minimal subset of the S3 REST API
+// (GetObject, HeadObject, ListObjects, ETag/If-None-Match handling),
+// backed by a httptest.Server. It lets tests exercise the DictSource
+// codepath (retries, change detection, checksums, listing) without touching
+// the network. The mock is read over plain HTTP through HttpDictSource; the
+// common/s3 client is not involved.
+type S3Mock struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// NewS3Mock starts an in-process mock S3 endpoint. Callers must call
+// Close() once done with it.
+func NewS3Mock() *S3Mock {
+	m := &S3Mock{objects: map[string][]byte{}}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	return m
+}
+
+// URL returns the base URL of the mock endpoint.
+func (m *S3Mock) URL() string {
+	return m.server.URL
+}
+
+// Close shuts down the mock endpoint.
+func (m *S3Mock) Close() {
+	m.server.Close()
+}
+
+// Seed adds or replaces an object in the mock bucket.
+func (m *S3Mock) Seed(key string, content []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = content
+}
+
+func (m *S3Mock) etag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func (m *S3Mock) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("list-type") == "2" {
+		m.handleList(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	m.mu.Lock()
+	content, ok := m.objects[key]
+	m.mu.Unlock()
+	if !ok {
+		http.Error(w, "NoSuchKey", http.StatusNotFound)
+		return
+	}
+
+	etag := m.etag(content)
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Write(content)
+}
+
+func (m *S3Mock) handleList(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	m.mu.Lock()
+	keys := make([]string, 0, len(m.objects))
+	for k := range m.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	m.mu.Unlock()
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult>`)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "<Contents><Key>%s</Key></Contents>", k)
+	}
+	buf.WriteString(`</ListBucketResult>`)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buf.Bytes())
+}
+
+func (m *S3Mock) getIfChanged(ctx context.Context, key string, prevETag string) (io.ReadCloser, string, bool, error) {
+	return HttpDictSource{}.getIfChanged(ctx, m.URL()+"/"+key, prevETag)
+}
+
+func (m *S3Mock) list(prefix string) ([]string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/?list-type=2&prefix=%s", m.URL(), url.QueryEscape(prefix)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, match := range strings.Split(string(data), "<Contents><Key>") {
+		if idx := strings.Index(match, "</Key>"); idx >= 0 {
+			keys = append(keys, match[:idx])
+		}
+	}
+	return keys, nil
+}
+
+// NewS3MockDictSource wraps an existing S3Mock, seeded by the caller via
+// Seed(), into a DictSource using the given fetch configuration.
+func NewS3MockDictSource(m *S3Mock, fetch FetchConfiguration) DictSource {
+	return wrappedDictSource{raw: m, config: fetch}
 }
 
 var dictSourceConfigurationMap = map[string](func() DictSourceConfiguration){
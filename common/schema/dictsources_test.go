@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schema
+
+import (
+	"context"
+	"io"
+	"sort"
+	"testing"
+)
+
+func TestS3MockDictSource(t *testing.T) {
+	mock := NewS3Mock()
+	defer mock.Close()
+	mock.Seed("dicts/asn.csv", []byte("1,example"))
+	mock.Seed("dicts/country.csv", []byte("FR,France"))
+
+	source := NewS3MockDictSource(mock, DefaultFetchConfiguration())
+	ctx := context.Background()
+
+	t.Run("Get", func(t *testing.T) {
+		rc, err := source.Get(ctx, "dicts/asn.csv")
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		defer rc.Close()
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("ReadAll() error: %v", err)
+		}
+		if got, want := string(content), "1,example"; got != want {
+			t.Errorf("Get() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		keys, err := source.List("dicts/")
+		if err != nil {
+			t.Fatalf("List() error: %v", err)
+		}
+		sort.Strings(keys)
+		want := []string{"dicts/asn.csv", "dicts/country.csv"}
+		if len(keys) != len(want) {
+			t.Fatalf("List() = %v, want %v", keys, want)
+		}
+		for i := range want {
+			if keys[i] != want[i] {
+				t.Errorf("List() = %v, want %v", keys, want)
+			}
+		}
+	})
+
+	t.Run("GetIfChanged", func(t *testing.T) {
+		rc, etag, notModified, err := source.GetIfChanged(ctx, "dicts/asn.csv", "")
+		if err != nil {
+			t.Fatalf("GetIfChanged() error: %v", err)
+		}
+		if notModified {
+			t.Fatalf("GetIfChanged() reported not modified on first fetch")
+		}
+		if etag == "" {
+			t.Fatalf("GetIfChanged() returned an empty ETag")
+		}
+		rc.Close()
+
+		rc, sameETag, notModified, err := source.GetIfChanged(ctx, "dicts/asn.csv", etag)
+		if err != nil {
+			t.Fatalf("GetIfChanged() error: %v", err)
+		}
+		if !notModified {
+			t.Fatalf("GetIfChanged() with a current ETag should report not modified")
+		}
+		if rc != nil {
+			t.Fatalf("GetIfChanged() should not return a reader when not modified")
+		}
+		if sameETag != etag {
+			t.Errorf("GetIfChanged() ETag = %q, want %q", sameETag, etag)
+		}
+
+		mock.Seed("dicts/asn.csv", []byte("1,updated"))
+		rc, newETag, notModified, err := source.GetIfChanged(ctx, "dicts/asn.csv", etag)
+		if err != nil {
+			t.Fatalf("GetIfChanged() error: %v", err)
+		}
+		if notModified {
+			t.Fatalf("GetIfChanged() should report a change after re-seeding the object")
+		}
+		defer rc.Close()
+		if newETag == etag {
+			t.Errorf("GetIfChanged() ETag did not change after re-seeding the object")
+		}
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("ReadAll() error: %v", err)
+		}
+		if got, want := string(content), "1,updated"; got != want {
+			t.Errorf("GetIfChanged() content = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routing
+
+import (
+	"akvorado/common/helpers"
+	"akvorado/inlet/routing/provider"
+	"akvorado/inlet/routing/provider/bmp"
+	"akvorado/inlet/routing/provider/mrt"
+)
+
+// Configuration describes the configuration for the routing component.
+type Configuration struct {
+	// Providers is the ordered list of providers to use to retrieve routing
+	// information. When it contains more than one entry, they are queried
+	// in order and the first one returning a non-empty result wins, unless
+	// Merge is set.
+	Providers []ProviderConfiguration `validate:"required,min=1,dive"`
+	// Merge, when true, merges the AS path and communities returned by all
+	// providers instead of stopping at the first non-empty result, while
+	// still preferring entries whose next hop matches the one requested.
+	Merge bool
+}
+
+// DefaultConfiguration returns the default configuration for the routing
+// component.
+func DefaultConfiguration() Configuration {
+	return Configuration{
+		Providers: []ProviderConfiguration{
+			{Config: bmp.DefaultConfiguration()},
+		},
+	}
+}
+
+// ProviderConfiguration represents the configuration for a routing provider.
+// It dispatches on the `type` key to the appropriate provider configuration,
+// following the same pattern as other parametrized configurations (see
+// common/schema.CustomDict).
+type ProviderConfiguration struct {
+	Config provider.Configuration
+}
+
+var providerConfigurationMap = map[string](func() provider.Configuration){
+	"bmp": func() provider.Configuration { return bmp.DefaultConfiguration() },
+	"mrt": func() provider.Configuration { return mrt.DefaultConfiguration() },
+}
+
+func init() {
+	helpers.RegisterMapstructureUnmarshallerHook(
+		helpers.ParametrizedConfigurationUnmarshallerHook(ProviderConfiguration{}, providerConfigurationMap))
+}
+
+// MarshalYAML undoes ConfigurationUnmarshallerHook().
+func (pc ProviderConfiguration) MarshalYAML() (interface{}, error) {
+	return helpers.ParametrizedConfigurationMarshalYAML(pc, providerConfigurationMap)
+}
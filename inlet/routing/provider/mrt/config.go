@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import "time"
+
+// Configuration describes the configuration for the MRT routing provider.
+type Configuration struct {
+	// Sources is the ordered list of places to fetch MRT RIB dumps from. Only
+	// the most recently modified object across all sources is loaded on each
+	// refresh.
+	Sources []SourceConfiguration `validate:"required,min=1,dive"`
+	// RefreshInterval is the time between two checks for a new dump.
+	RefreshInterval time.Duration `validate:"min=1m"`
+}
+
+// SourceConfiguration describes where to fetch MRT dumps from. Exactly one of
+// Path, URL or S3 should be set.
+type SourceConfiguration struct {
+	// Path is a path to a local file or a directory containing dumps.
+	Path string
+	// URL is an HTTP(S) URL to a single dump.
+	URL string
+	// S3 is the configuration to fetch dumps from an S3 bucket.
+	S3 *S3SourceConfiguration
+}
+
+// S3SourceConfiguration describes an S3 bucket and prefix to fetch MRT dumps
+// from, using the common/s3 component.
+type S3SourceConfiguration struct {
+	// Config is the name of the S3 configuration to use (see common/s3).
+	Config string
+	// Prefix restricts the listing to objects below this prefix.
+	Prefix string
+}
+
+// DefaultConfiguration returns the default configuration for the MRT routing
+// provider.
+func DefaultConfiguration() *Configuration {
+	return &Configuration{
+		RefreshInterval: 10 * time.Minute,
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"akvorado/common/reporter"
+)
+
+type metrics struct {
+	recordsParsed prometheus.Counter
+	bytesFetched  prometheus.Counter
+	refreshErrors prometheus.Counter
+	lastSuccess   prometheus.Gauge
+	routesLoaded  prometheus.Gauge
+}
+
+func newMetrics(r *reporter.Reporter) *metrics {
+	return &metrics{
+		recordsParsed: promauto.With(r.MetricsRegistry()).NewCounter(
+			prometheus.CounterOpts{
+				Name: "records_parsed_total",
+				Help: "Number of MRT records parsed.",
+			}),
+		bytesFetched: promauto.With(r.MetricsRegistry()).NewCounter(
+			prometheus.CounterOpts{
+				Name: "bytes_fetched_total",
+				Help: "Number of bytes fetched from the configured sources.",
+			}),
+		refreshErrors: promauto.With(r.MetricsRegistry()).NewCounter(
+			prometheus.CounterOpts{
+				Name: "refresh_errors_total",
+				Help: "Number of failed refresh attempts.",
+			}),
+		lastSuccess: promauto.With(r.MetricsRegistry()).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "last_success_timestamp_seconds",
+				Help: "Timestamp of the last successful refresh.",
+			}),
+		routesLoaded: promauto.With(r.MetricsRegistry()).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "routes_loaded",
+				Help: "Number of routes currently loaded in the RIB.",
+			}),
+	}
+}
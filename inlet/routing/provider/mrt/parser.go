@@ -0,0 +1,402 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// MRT record types/subtypes we understand (RFC 6396).
+const (
+	mrtTypeTableDumpV2 = 13
+
+	mrtSubtypePeerIndexTable = 1
+	mrtSubtypeRIBIPv4Unicast = 2
+	mrtSubtypeRIBIPv6Unicast = 4
+)
+
+// BGP path attribute type codes we understand.
+const (
+	attrOrigin              = 1
+	attrASPath              = 2
+	attrNextHop             = 3
+	attrCommunity           = 8
+	attrMPReachNLRI         = 14
+	attrExtendedCommunities = 16
+	attrAS4Path             = 17
+	attrLargeCommunity      = 32
+)
+
+const (
+	asPathSegmentSet       = 1
+	asPathSegmentSequence  = 2
+	asPathSegmentConfedSeq = 3
+	asPathSegmentConfedSet = 4
+)
+
+// peerEntry describes one peer from a PEER_INDEX_TABLE record.
+type peerEntry struct {
+	asSize int // 2 or 4
+	ip     netip.Addr
+	asn    uint32
+}
+
+// parseResult accumulates what was learned from a single MRT dump.
+type parseResult struct {
+	records int
+	bytes   int64
+	routes  []route
+}
+
+// parseMRTDump reads a full MRT TABLE_DUMP_V2 stream and returns every RIB
+// entry found, resolved against the peer index table that must precede them.
+func parseMRTDump(r io.Reader) (parseResult, error) {
+	cr := &countingReader{r: r}
+	var peers []peerEntry
+	var result parseResult
+	for {
+		header, payload, err := readMRTRecord(cr)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("cannot read MRT record: %w", err)
+		}
+		result.records++
+		if header.recordType != mrtTypeTableDumpV2 {
+			continue
+		}
+		switch header.subtype {
+		case mrtSubtypePeerIndexTable:
+			peers, err = parsePeerIndexTable(payload)
+			if err != nil {
+				return result, fmt.Errorf("cannot parse peer index table: %w", err)
+			}
+		case mrtSubtypeRIBIPv4Unicast, mrtSubtypeRIBIPv6Unicast:
+			isIPv6 := header.subtype == mrtSubtypeRIBIPv6Unicast
+			routes, err := parseRIBEntry(payload, peers, isIPv6)
+			if err != nil {
+				return result, fmt.Errorf("cannot parse RIB entry: %w", err)
+			}
+			result.routes = append(result.routes, routes...)
+		}
+	}
+	result.bytes = cr.n
+	return result, nil
+}
+
+type mrtHeader struct {
+	recordType uint16
+	subtype    uint16
+}
+
+func readMRTRecord(r io.Reader) (mrtHeader, []byte, error) {
+	var fixed [12]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return mrtHeader{}, nil, io.EOF
+		}
+		return mrtHeader{}, nil, err
+	}
+	header := mrtHeader{
+		recordType: binary.BigEndian.Uint16(fixed[4:6]),
+		subtype:    binary.BigEndian.Uint16(fixed[6:8]),
+	}
+	length := binary.BigEndian.Uint32(fixed[8:12])
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return mrtHeader{}, nil, fmt.Errorf("truncated record payload: %w", err)
+	}
+	return header, payload, nil
+}
+
+func parsePeerIndexTable(payload []byte) ([]peerEntry, error) {
+	if len(payload) < 6 {
+		return nil, fmt.Errorf("short peer index table")
+	}
+	viewNameLength := binary.BigEndian.Uint16(payload[4:6])
+	offset := 6 + int(viewNameLength)
+	if offset+2 > len(payload) {
+		return nil, fmt.Errorf("short peer index table view name")
+	}
+	peerCount := binary.BigEndian.Uint16(payload[offset : offset+2])
+	offset += 2
+	peers := make([]peerEntry, 0, peerCount)
+	for i := 0; i < int(peerCount); i++ {
+		if offset+1 > len(payload) {
+			return nil, fmt.Errorf("short peer entry")
+		}
+		peerType := payload[offset]
+		offset++
+		offset += 4 // peer BGP ID
+		var ip netip.Addr
+		var ok bool
+		if peerType&0x1 != 0 {
+			if offset+16 > len(payload) {
+				return nil, fmt.Errorf("short peer IPv6 address")
+			}
+			ip, ok = netip.AddrFromSlice(payload[offset : offset+16])
+			offset += 16
+		} else {
+			if offset+4 > len(payload) {
+				return nil, fmt.Errorf("short peer IPv4 address")
+			}
+			ip, ok = netip.AddrFromSlice(payload[offset : offset+4])
+			offset += 4
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid peer address")
+		}
+		asSize := 2
+		if peerType&0x2 != 0 {
+			asSize = 4
+		}
+		var asn uint32
+		if asSize == 4 {
+			if offset+4 > len(payload) {
+				return nil, fmt.Errorf("short peer AS")
+			}
+			asn = binary.BigEndian.Uint32(payload[offset : offset+4])
+			offset += 4
+		} else {
+			if offset+2 > len(payload) {
+				return nil, fmt.Errorf("short peer AS")
+			}
+			asn = uint32(binary.BigEndian.Uint16(payload[offset : offset+2]))
+			offset += 2
+		}
+		peers = append(peers, peerEntry{asSize: asSize, ip: ip, asn: asn})
+	}
+	return peers, nil
+}
+
+func parseRIBEntry(payload []byte, peers []peerEntry, isIPv6 bool) ([]route, error) {
+	if len(payload) < 5 {
+		return nil, fmt.Errorf("short RIB entry")
+	}
+	offset := 4 // sequence number
+	prefixBits := int(payload[offset])
+	offset++
+	prefixBytes := (prefixBits + 7) / 8
+	if offset+prefixBytes > len(payload) {
+		return nil, fmt.Errorf("short prefix")
+	}
+	addrBytes := make([]byte, 4)
+	if isIPv6 {
+		addrBytes = make([]byte, 16)
+	}
+	copy(addrBytes, payload[offset:offset+prefixBytes])
+	offset += prefixBytes
+	addr, ok := netip.AddrFromSlice(addrBytes)
+	if !ok {
+		return nil, fmt.Errorf("invalid prefix address")
+	}
+	prefix := netip.PrefixFrom(addr, prefixBits)
+
+	if offset+2 > len(payload) {
+		return nil, fmt.Errorf("short entry count")
+	}
+	entryCount := binary.BigEndian.Uint16(payload[offset : offset+2])
+	offset += 2
+
+	routes := make([]route, 0, entryCount)
+	for i := 0; i < int(entryCount); i++ {
+		if offset+8 > len(payload) {
+			return nil, fmt.Errorf("short RIB entry header")
+		}
+		peerIndex := binary.BigEndian.Uint16(payload[offset : offset+2])
+		offset += 2
+		offset += 4 // originated time
+		attrLength := binary.BigEndian.Uint16(payload[offset : offset+2])
+		offset += 2
+		if offset+int(attrLength) > len(payload) {
+			return nil, fmt.Errorf("short RIB entry attributes")
+		}
+		asSize := 4
+		if int(peerIndex) < len(peers) {
+			asSize = peers[int(peerIndex)].asSize
+		}
+		attrs, err := parseBGPAttributes(payload[offset:offset+int(attrLength)], asSize)
+		if err != nil {
+			return nil, fmt.Errorf("peer %d: %w", peerIndex, err)
+		}
+		offset += int(attrLength)
+		r := route{
+			prefix:              prefix,
+			originASN:           attrs.originASN,
+			asPath:              attrs.asPath,
+			communities:         attrs.communities,
+			largeCommunities:    attrs.largeCommunities,
+			extendedCommunities: attrs.extendedCommunities,
+			nextHop:             attrs.nextHop,
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+type bgpAttributes struct {
+	originASN           uint32
+	asPath              []uint32
+	communities         []uint32
+	largeCommunities    []largeCommunity
+	extendedCommunities []extendedCommunity
+	nextHop             netip.Addr
+}
+
+// parseBGPAttributes parses the BGP path attributes attached to a RIB entry,
+// flattening AS_SET and confederation segments found in AS_PATH into a
+// single ordered slice and keeping the last AS as the origin.
+func parseBGPAttributes(data []byte, asSize int) (bgpAttributes, error) {
+	var attrs bgpAttributes
+	offset := 0
+	for offset < len(data) {
+		if offset+2 > len(data) {
+			return attrs, fmt.Errorf("short attribute header")
+		}
+		flags := data[offset]
+		code := data[offset+1]
+		offset += 2
+		extendedLength := flags&0x10 != 0
+		var length int
+		if extendedLength {
+			if offset+2 > len(data) {
+				return attrs, fmt.Errorf("short extended attribute length")
+			}
+			length = int(binary.BigEndian.Uint16(data[offset : offset+2]))
+			offset += 2
+		} else {
+			if offset+1 > len(data) {
+				return attrs, fmt.Errorf("short attribute length")
+			}
+			length = int(data[offset])
+			offset++
+		}
+		if offset+length > len(data) {
+			return attrs, fmt.Errorf("short attribute value")
+		}
+		value := data[offset : offset+length]
+		offset += length
+
+		switch code {
+		case attrASPath, attrAS4Path:
+			// AS4_PATH is always 4-byte-ASN encoded (RFC 6793), regardless of
+			// the peer's declared AS size.
+			pathASSize := asSize
+			if code == attrAS4Path {
+				pathASSize = 4
+			}
+			path, err := parseASPath(value, pathASSize)
+			if err != nil {
+				return attrs, fmt.Errorf("AS_PATH: %w", err)
+			}
+			if len(path) > 0 {
+				attrs.asPath = path
+				attrs.originASN = path[len(path)-1]
+			}
+		case attrNextHop:
+			if addr, ok := netip.AddrFromSlice(value); ok {
+				attrs.nextHop = addr
+			}
+		case attrMPReachNLRI:
+			if nh, ok := parseMPReachNextHop(value); ok {
+				attrs.nextHop = nh
+			}
+		case attrCommunity:
+			for i := 0; i+4 <= len(value); i += 4 {
+				attrs.communities = append(attrs.communities, binary.BigEndian.Uint32(value[i:i+4]))
+			}
+		case attrLargeCommunity:
+			for i := 0; i+12 <= len(value); i += 12 {
+				attrs.largeCommunities = append(attrs.largeCommunities, largeCommunity{
+					asn:        binary.BigEndian.Uint32(value[i : i+4]),
+					localData1: binary.BigEndian.Uint32(value[i+4 : i+8]),
+					localData2: binary.BigEndian.Uint32(value[i+8 : i+12]),
+				})
+			}
+		case attrExtendedCommunities:
+			// Extended communities are 8 bytes each: a 1-byte type followed
+			// by a 7-byte value. Keep them as their own type instead of
+			// conflating them with plain (4-byte) communities.
+			for i := 0; i+8 <= len(value); i += 8 {
+				var ec extendedCommunity
+				ec.typ = value[i]
+				copy(ec.value[:], value[i+1:i+8])
+				attrs.extendedCommunities = append(attrs.extendedCommunities, ec)
+			}
+		}
+	}
+	return attrs, nil
+}
+
+func parseASPath(value []byte, asSize int) ([]uint32, error) {
+	var path []uint32
+	offset := 0
+	for offset < len(value) {
+		if offset+2 > len(value) {
+			return nil, fmt.Errorf("short AS_PATH segment header")
+		}
+		segType := value[offset]
+		segCount := int(value[offset+1])
+		offset += 2
+		segment := make([]uint32, 0, segCount)
+		for i := 0; i < segCount; i++ {
+			if asSize == 4 {
+				if offset+4 > len(value) {
+					return nil, fmt.Errorf("short AS_PATH AS number")
+				}
+				segment = append(segment, binary.BigEndian.Uint32(value[offset:offset+4]))
+				offset += 4
+			} else {
+				if offset+2 > len(value) {
+					return nil, fmt.Errorf("short AS_PATH AS number")
+				}
+				segment = append(segment, uint32(binary.BigEndian.Uint16(value[offset:offset+2])))
+				offset += 2
+			}
+		}
+		switch segType {
+		case asPathSegmentSequence, asPathSegmentConfedSeq:
+			path = append(path, segment...)
+		case asPathSegmentSet, asPathSegmentConfedSet:
+			// AS_SET/confederation set: flatten in the order seen. The exact
+			// order inside a set is not meaningful, only membership.
+			path = append(path, segment...)
+		}
+	}
+	return path, nil
+}
+
+func parseMPReachNextHop(value []byte) (netip.Addr, bool) {
+	if len(value) < 5 {
+		return netip.Addr{}, false
+	}
+	nhLength := int(value[3])
+	if 4+nhLength > len(value) {
+		return netip.Addr{}, false
+	}
+	nh := value[4 : 4+nhLength]
+	// A link-local next-hop may be appended for IPv6; keep only the first,
+	// global address.
+	if nhLength == 32 {
+		nh = nh[:16]
+	}
+	return netip.AddrFromSlice(nh)
+}
+
+// countingReader wraps an io.Reader to keep track of the number of bytes
+// read, used to report fetched bytes as a metric.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func writeMRTRecord(buf *bytes.Buffer, recordType, subtype uint16, payload []byte) {
+	buf.Write(make([]byte, 4)) // timestamp, unused by the parser
+	buf.Write(u16(recordType))
+	buf.Write(u16(subtype))
+	buf.Write(u32(uint32(len(payload))))
+	buf.Write(payload)
+}
+
+// buildPeerIndexTable builds a minimal PEER_INDEX_TABLE payload with one
+// IPv4 peer of the given AS size.
+func buildPeerIndexTable(ip netip.Addr, asSize int, asn uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4)) // collector BGP ID
+	buf.Write(u16(0))          // view name length
+	buf.Write(u16(1))          // peer count
+	peerType := byte(0)
+	if asSize == 4 {
+		peerType |= 0x2
+	}
+	buf.WriteByte(peerType)
+	buf.Write(make([]byte, 4)) // peer BGP ID
+	buf.Write(ip.AsSlice())
+	if asSize == 4 {
+		buf.Write(u32(asn))
+	} else {
+		buf.Write(u16(uint16(asn)))
+	}
+	return buf.Bytes()
+}
+
+// buildAttr encodes a single BGP path attribute (no extended length).
+func buildAttr(code byte, value []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(0)
+	buf.WriteByte(code)
+	buf.WriteByte(byte(len(value)))
+	buf.Write(value)
+	return buf.Bytes()
+}
+
+// buildASPathValue encodes a single AS_SEQUENCE segment with asns encoded at
+// the given AS size.
+func buildASPathValue(asSize int, asns []uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(asPathSegmentSequence)
+	buf.WriteByte(byte(len(asns)))
+	for _, asn := range asns {
+		if asSize == 4 {
+			buf.Write(u32(asn))
+		} else {
+			buf.Write(u16(uint16(asn)))
+		}
+	}
+	return buf.Bytes()
+}
+
+// buildRIBEntry builds a RIB_IPV4_UNICAST payload with a single prefix and
+// the given per-peer attributes.
+func buildRIBEntry(prefix netip.Prefix, peerIndex uint16, attrs []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4)) // sequence number
+	bits := prefix.Bits()
+	buf.WriteByte(byte(bits))
+	addrBytes := prefix.Addr().AsSlice()
+	buf.Write(addrBytes[:(bits+7)/8])
+	buf.Write(u16(1)) // entry count
+	buf.Write(u16(peerIndex))
+	buf.Write(make([]byte, 4)) // originated time
+	buf.Write(u16(uint16(len(attrs))))
+	buf.Write(attrs)
+	return buf.Bytes()
+}
+
+// TestParseMRTDumpAS4Path exercises a 2-byte-AS peer whose RIB entry carries
+// an AS4_PATH attribute: AS4_PATH is always 4-byte-ASN encoded regardless of
+// the peer's declared AS size, and must not be parsed using the peer's
+// asSize.
+func TestParseMRTDumpAS4Path(t *testing.T) {
+	peerIP := netip.MustParseAddr("192.0.2.1")
+	peerIndexPayload := buildPeerIndexTable(peerIP, 2, 65000)
+
+	asPathAttr := buildAttr(attrASPath, buildASPathValue(2, []uint32{65001}))
+	as4PathAttr := buildAttr(attrAS4Path, buildASPathValue(4, []uint32{4200000000}))
+	attrs := append(append([]byte{}, asPathAttr...), as4PathAttr...)
+
+	prefix := netip.MustParsePrefix("192.0.2.0/24")
+	ribPayload := buildRIBEntry(prefix, 0, attrs)
+
+	var buf bytes.Buffer
+	writeMRTRecord(&buf, mrtTypeTableDumpV2, mrtSubtypePeerIndexTable, peerIndexPayload)
+	writeMRTRecord(&buf, mrtTypeTableDumpV2, mrtSubtypeRIBIPv4Unicast, ribPayload)
+
+	result, err := parseMRTDump(&buf)
+	if err != nil {
+		t.Fatalf("parseMRTDump() error: %v", err)
+	}
+	if len(result.routes) != 1 {
+		t.Fatalf("parseMRTDump() returned %d routes, want 1", len(result.routes))
+	}
+	r := result.routes[0]
+	if r.prefix != prefix {
+		t.Errorf("route prefix = %s, want %s", r.prefix, prefix)
+	}
+	if r.originASN != 4200000000 {
+		t.Errorf("route originASN = %d, want 4200000000 (from AS4_PATH, not misparsed using the peer's 2-byte AS size)", r.originASN)
+	}
+	if len(r.asPath) != 1 || r.asPath[0] != 4200000000 {
+		t.Errorf("route asPath = %v, want [4200000000]", r.asPath)
+	}
+}
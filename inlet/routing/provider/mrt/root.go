@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package mrt implements a routing provider sourcing its RIB from
+// periodically-refreshed MRT `TABLE_DUMP_V2` dumps (as produced by
+// RouteViews or RIPE RIS), instead of a live BGP session. It is a drop-in
+// replacement for (or fallback to) the `bmp` provider wherever a live
+// session is not available or desirable.
+package mrt
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"akvorado/common/reporter"
+	"akvorado/inlet/routing/provider"
+)
+
+// Component represents the MRT routing provider.
+type Component struct {
+	r       *reporter.Reporter
+	config  *Configuration
+	sources []source
+	metrics *metrics
+
+	t       *time.Ticker
+	done    chan struct{}
+	stopped chan struct{}
+
+	// rib holds the current, immutable trie. It is swapped atomically on
+	// each successful refresh so Lookup() never blocks on a refresh in
+	// progress.
+	rib atomic.Pointer[trie]
+}
+
+// New creates a new MRT routing provider from its configuration.
+func (configuration *Configuration) New(r *reporter.Reporter, dependencies provider.Dependencies) (provider.Provider, error) {
+	sources, err := newSources(configuration, dependencies.S3)
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure MRT sources: %w", err)
+	}
+	c := Component{
+		r:       r,
+		config:  configuration,
+		sources: sources,
+		metrics: newMetrics(r),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	c.rib.Store(newTrie())
+	return &c, nil
+}
+
+// Start starts the periodic refresh of the MRT RIB.
+func (c *Component) Start() error {
+	c.r.Info().Msg("starting MRT routing provider")
+	if err := c.refresh(); err != nil {
+		c.r.Err(err).Msg("initial MRT refresh failed, will retry on next tick")
+	}
+	c.t = time.NewTicker(c.config.RefreshInterval)
+	go func() {
+		defer close(c.stopped)
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-c.t.C:
+				if err := c.refresh(); err != nil {
+					c.r.Err(err).Msg("MRT refresh failed")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the periodic refresh of the MRT RIB.
+func (c *Component) Stop() error {
+	c.r.Info().Msg("stopping MRT routing provider")
+	if c.t != nil {
+		c.t.Stop()
+	}
+	close(c.done)
+	<-c.stopped
+	return nil
+}
+
+// refresh looks for the newest dump across all configured sources, parses
+// it and, on success, atomically swaps it in as the current RIB.
+func (c *Component) refresh() error {
+	obj, found, err := c.newestObject()
+	if err != nil {
+		c.metrics.refreshErrors.Inc()
+		return fmt.Errorf("cannot list sources: %w", err)
+	}
+	if !found {
+		c.metrics.refreshErrors.Inc()
+		return fmt.Errorf("no MRT dump found in the configured sources")
+	}
+
+	reader, err := obj.open()
+	if err != nil {
+		c.metrics.refreshErrors.Inc()
+		return fmt.Errorf("cannot fetch %q: %w", obj.name, err)
+	}
+	defer reader.Close()
+
+	result, err := parseMRTDump(reader)
+	if err != nil {
+		c.metrics.refreshErrors.Inc()
+		return fmt.Errorf("cannot parse %q: %w", obj.name, err)
+	}
+
+	t := newTrie()
+	for _, route := range result.routes {
+		t.insert(route)
+	}
+	c.rib.Store(t)
+
+	c.metrics.recordsParsed.Add(float64(result.records))
+	c.metrics.bytesFetched.Add(float64(result.bytes))
+	c.metrics.routesLoaded.Set(float64(len(result.routes)))
+	c.metrics.lastSuccess.SetToCurrentTime()
+	c.r.Info().Str("source", obj.name).Int("routes", len(result.routes)).Msg("MRT RIB refreshed")
+	return nil
+}
+
+// Lookup returns routing information for the provided IP, using nh, if
+// valid, to disambiguate between several routes to the same prefix. It
+// returns an empty result when no RIB has been loaded yet or when no route
+// matches.
+func (c *Component) Lookup(_ context.Context, ip netip.Addr, nh netip.Addr) provider.LookupResult {
+	t := c.rib.Load()
+	if t == nil {
+		return provider.LookupResult{}
+	}
+	r, ok := t.lookup(ip, nh)
+	if !ok {
+		return provider.LookupResult{}
+	}
+	largeCommunities := make([]provider.LargeCommunity, 0, len(r.largeCommunities))
+	for _, lc := range r.largeCommunities {
+		largeCommunities = append(largeCommunities, provider.LargeCommunity{
+			ASN:        lc.asn,
+			LocalData1: lc.localData1,
+			LocalData2: lc.localData2,
+		})
+	}
+	extendedCommunities := make([]provider.ExtendedCommunity, 0, len(r.extendedCommunities))
+	for _, ec := range r.extendedCommunities {
+		extendedCommunities = append(extendedCommunities, provider.ExtendedCommunity{
+			Type:  ec.typ,
+			Value: ec.value,
+		})
+	}
+	return provider.LookupResult{
+		ASN:                 r.originASN,
+		ASPath:              r.asPath,
+		Communities:         r.communities,
+		LargeCommunities:    largeCommunities,
+		ExtendedCommunities: extendedCommunities,
+		NextHop:             r.nextHop,
+	}
+}
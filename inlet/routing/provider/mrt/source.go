@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"akvorado/common/s3"
+)
+
+// object describes a candidate MRT dump, as found by one of the sources.
+type object struct {
+	// name is a human-readable identifier, used for logging.
+	name string
+	// lastModified is used to pick the newest object across all sources.
+	lastModified time.Time
+	// open opens the object content. The caller is responsible for closing it.
+	open func() (io.ReadCloser, error)
+}
+
+// source is something able to list the MRT dumps it can provide.
+type source interface {
+	list() ([]object, error)
+}
+
+// newestObject lists every configured source and returns the most recently
+// modified object, or false if none was found.
+func (c *Component) newestObject() (object, bool, error) {
+	var newest object
+	found := false
+	for i, src := range c.sources {
+		objects, err := src.list()
+		if err != nil {
+			return object{}, false, fmt.Errorf("source %d: %w", i, err)
+		}
+		for _, o := range objects {
+			if !found || o.lastModified.After(newest.lastModified) {
+				newest = o
+				found = true
+			}
+		}
+	}
+	return newest, found, nil
+}
+
+// fileSource lists MRT dumps from a local file or directory.
+type fileSource struct {
+	path string
+}
+
+func (fs fileSource) list() ([]object, error) {
+	info, err := os.Stat(fs.path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []object{{
+			name:         fs.path,
+			lastModified: info.ModTime(),
+			open:         func() (io.ReadCloser, error) { return os.Open(fs.path) },
+		}}, nil
+	}
+	entries, err := os.ReadDir(fs.path)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		fullPath := filepath.Join(fs.path, entry.Name())
+		objects = append(objects, object{
+			name:         fullPath,
+			lastModified: entryInfo.ModTime(),
+			open:         func() (io.ReadCloser, error) { return os.Open(fullPath) },
+		})
+	}
+	return objects, nil
+}
+
+// httpSource fetches a single MRT dump from an HTTP(S) URL. As plain HTTP
+// does not provide a listing, we use the `Last-Modified` response header
+// (falling back to the current time) to let it compete with other sources.
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+func (hs httpSource) list() ([]object, error) {
+	resp, err := hs.client.Head(hs.url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot HEAD %q: %w", hs.url, err)
+	}
+	resp.Body.Close()
+	lastModified := time.Now()
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if parsed, err := http.ParseTime(lm); err == nil {
+			lastModified = parsed
+		}
+	}
+	url := hs.url
+	client := hs.client
+	return []object{{
+		name:         url,
+		lastModified: lastModified,
+		open: func() (io.ReadCloser, error) {
+			resp, err := client.Get(url)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("unexpected status %s fetching %q", resp.Status, url)
+			}
+			return resp.Body, nil
+		},
+	}}, nil
+}
+
+// s3Source lists MRT dumps below a prefix in an S3 bucket.
+type s3Source struct {
+	component *s3.Component
+	config    string
+	prefix    string
+}
+
+func (ss s3Source) list() ([]object, error) {
+	keys, err := ss.component.ListObjects(ss.config, ss.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list objects for %q: %w", ss.config, err)
+	}
+	sort.Strings(keys)
+	objects := make([]object, 0, len(keys))
+	for _, key := range keys {
+		key := key
+		_, lastModified, err := ss.component.HeadObject(ss.config, key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot stat object %q: %w", key, err)
+		}
+		objects = append(objects, object{
+			name:         fmt.Sprintf("s3://%s/%s", ss.config, key),
+			lastModified: lastModified,
+			open: func() (io.ReadCloser, error) {
+				return ss.component.GetObject(ss.config, key)
+			},
+		})
+	}
+	return objects, nil
+}
+
+// newSources builds the list of sources from the provider configuration.
+func newSources(configuration *Configuration, s3Component *s3.Component) ([]source, error) {
+	sources := make([]source, 0, len(configuration.Sources))
+	for i, sc := range configuration.Sources {
+		switch {
+		case sc.Path != "":
+			sources = append(sources, fileSource{path: sc.Path})
+		case sc.URL != "":
+			sources = append(sources, httpSource{url: sc.URL, client: &http.Client{Timeout: time.Minute}})
+		case sc.S3 != nil:
+			sources = append(sources, s3Source{
+				component: s3Component,
+				config:    sc.S3.Config,
+				prefix:    sc.S3.Prefix,
+			})
+		default:
+			return nil, fmt.Errorf("source %d: no path, URL or S3 bucket configured", i)
+		}
+	}
+	return sources, nil
+}
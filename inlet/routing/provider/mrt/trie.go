@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import "net/netip"
+
+// route is the routing information attached to a prefix in the trie.
+type route struct {
+	prefix              netip.Prefix
+	originASN           uint32
+	asPath              []uint32
+	communities         []uint32
+	largeCommunities    []largeCommunity
+	extendedCommunities []extendedCommunity
+	nextHop             netip.Addr
+}
+
+// extendedCommunity is a BGP extended community (RFC 4360): an opaque 8-byte
+// value whose first byte carries its type and is kept separate from its
+// 7-byte payload instead of being discarded.
+type extendedCommunity struct {
+	typ   uint8
+	value [7]byte
+}
+
+type largeCommunity struct {
+	asn        uint32
+	localData1 uint32
+	localData2 uint32
+}
+
+// trie is an immutable longest-prefix-match radix trie. It is built once per
+// refresh and never mutated afterwards, so it can be looked up concurrently
+// with a refresh in progress: Lookup only ever sees a fully built trie,
+// swapped atomically into place by the caller.
+type trie struct {
+	v4 *trieNode
+	v6 *trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	// routes holds every route ending exactly at this node: normally a
+	// single one, but several when multiple peers announce the same exact
+	// prefix with different next hops, which is what nh in lookup()
+	// disambiguates between.
+	routes []route
+}
+
+func newTrie() *trie {
+	return &trie{v4: &trieNode{}, v6: &trieNode{}}
+}
+
+// insert adds a route to the trie, creating intermediate nodes as needed. A
+// route replaces any existing one at the same node that shares its next
+// hop, and is appended otherwise, so several peers announcing the same
+// prefix with distinct next hops are all kept for lookup() to pick from.
+func (t *trie) insert(r route) {
+	root := t.v4
+	if r.prefix.Addr().Is6() {
+		root = t.v6
+	}
+	bits := r.prefix.Addr().AsSlice()
+	node := root
+	for i := 0; i < r.prefix.Bits(); i++ {
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+	for i, existing := range node.routes {
+		if existing.nextHop == r.nextHop {
+			node.routes[i] = r
+			return
+		}
+	}
+	node.routes = append(node.routes, r)
+}
+
+// lookup returns the most specific route matching ip, and whether one was
+// found. Among the routes tied at that longest-matching prefix, it prefers
+// the one whose nextHop matches nh, when nh is valid, to disambiguate
+// multi-homed peers announcing the same prefix. nh never lets a less
+// specific prefix win over a more specific one.
+func (t *trie) lookup(ip netip.Addr, nh netip.Addr) (route, bool) {
+	root := t.v4
+	if ip.Is6() {
+		root = t.v6
+	}
+	bits := ip.AsSlice()
+	node := root
+	var bestNode *trieNode
+	for i := 0; i < len(bits)*8; i++ {
+		if len(node.routes) > 0 {
+			bestNode = node
+		}
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		if node.children[bit] == nil {
+			break
+		}
+		node = node.children[bit]
+	}
+	if len(node.routes) > 0 {
+		bestNode = node
+	}
+	if bestNode == nil {
+		return route{}, false
+	}
+	if nh.IsValid() {
+		for _, r := range bestNode.routes {
+			if r.nextHop == nh {
+				return r, true
+			}
+		}
+	}
+	return bestNode.routes[0], true
+}
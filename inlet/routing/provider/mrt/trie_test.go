@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mrt
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTrieLongestPrefixOverNextHop(t *testing.T) {
+	tr := newTrie()
+	broad := route{
+		prefix:    netip.MustParsePrefix("10.0.0.0/8"),
+		nextHop:   netip.MustParseAddr("1.1.1.1"),
+		originASN: 100,
+	}
+	narrow := route{
+		prefix:    netip.MustParsePrefix("10.0.0.0/24"),
+		nextHop:   netip.MustParseAddr("2.2.2.2"),
+		originASN: 200,
+	}
+	tr.insert(broad)
+	tr.insert(narrow)
+
+	// nh matches the broader route, but the narrower one is a longer match
+	// and must win regardless.
+	got, ok := tr.lookup(netip.MustParseAddr("10.0.0.5"), netip.MustParseAddr("1.1.1.1"))
+	if !ok {
+		t.Fatalf("lookup() found no match")
+	}
+	if got.originASN != 200 {
+		t.Errorf("lookup() = originASN %d, want 200 (longest prefix should win over nh)", got.originASN)
+	}
+
+	// Outside the narrower prefix, the broader route is the only match.
+	got, ok = tr.lookup(netip.MustParseAddr("10.1.0.5"), netip.MustParseAddr("1.1.1.1"))
+	if !ok {
+		t.Fatalf("lookup() found no match")
+	}
+	if got.originASN != 100 {
+		t.Errorf("lookup() = originASN %d, want 100", got.originASN)
+	}
+}
+
+func TestTrieNextHopTiebreak(t *testing.T) {
+	tr := newTrie()
+	prefix := netip.MustParsePrefix("10.0.1.0/24")
+	routeA := route{prefix: prefix, nextHop: netip.MustParseAddr("3.3.3.3"), originASN: 300}
+	routeB := route{prefix: prefix, nextHop: netip.MustParseAddr("4.4.4.4"), originASN: 400}
+	tr.insert(routeA)
+	tr.insert(routeB)
+
+	got, ok := tr.lookup(netip.MustParseAddr("10.0.1.1"), netip.MustParseAddr("4.4.4.4"))
+	if !ok {
+		t.Fatalf("lookup() found no match")
+	}
+	if got.originASN != 400 {
+		t.Errorf("lookup() with nh=4.4.4.4 = originASN %d, want 400", got.originASN)
+	}
+
+	got, ok = tr.lookup(netip.MustParseAddr("10.0.1.1"), netip.Addr{})
+	if !ok {
+		t.Fatalf("lookup() found no match")
+	}
+	if got.originASN != 300 {
+		t.Errorf("lookup() with no nh = originASN %d, want 300 (first inserted)", got.originASN)
+	}
+}
+
+func TestTrieInsertReplacesSameNextHop(t *testing.T) {
+	tr := newTrie()
+	prefix := netip.MustParsePrefix("192.0.2.0/24")
+	nh := netip.MustParseAddr("5.5.5.5")
+	tr.insert(route{prefix: prefix, nextHop: nh, originASN: 10})
+	tr.insert(route{prefix: prefix, nextHop: nh, originASN: 20})
+
+	got, ok := tr.lookup(netip.MustParseAddr("192.0.2.1"), nh)
+	if !ok {
+		t.Fatalf("lookup() found no match")
+	}
+	if got.originASN != 20 {
+		t.Errorf("lookup() = originASN %d, want 20 (second insert should replace the first)", got.originASN)
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package multi composes several routing providers into a single one. It is
+// used whenever more than one provider is configured, for example to run BMP
+// as the authoritative source while falling back to a static file or an MRT
+// provider while BMP is still converging after an inlet restart.
+package multi
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"akvorado/inlet/routing/provider"
+)
+
+// Component is a routing provider composing several other providers.
+type Component struct {
+	children []provider.Provider
+	names    []string
+	merge    bool
+}
+
+// New creates a provider composing the provided children, queried in order.
+// names is used to identify each child in error messages and must have the
+// same length as children. When merge is true, Lookup unions the AS path and
+// communities of every child returning a result instead of stopping at the
+// first non-empty one.
+func New(children []provider.Provider, names []string, merge bool) (*Component, error) {
+	if len(children) == 0 {
+		return nil, fmt.Errorf("no provider configured")
+	}
+	if len(names) != len(children) {
+		return nil, fmt.Errorf("internal error: got %d providers but %d names", len(children), len(names))
+	}
+	return &Component{children: children, names: names, merge: merge}, nil
+}
+
+// Start starts every child provider that implements it, in order. If a child
+// fails to start, the ones already started are stopped before returning, and
+// the error is annotated with the failing child's name.
+func (c *Component) Start() error {
+	for i, child := range c.children {
+		starterP, ok := child.(starter)
+		if !ok {
+			continue
+		}
+		if err := starterP.Start(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if stopperP, ok := c.children[j].(stopper); ok {
+					stopperP.Stop()
+				}
+			}
+			return fmt.Errorf("provider %s: %w", c.names[i], err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every child provider that implements it, in order. It keeps
+// going even if a child fails to stop, returning the first error seen,
+// annotated with the failing child's name.
+func (c *Component) Stop() error {
+	var firstErr error
+	for i, child := range c.children {
+		stopperP, ok := child.(stopper)
+		if !ok {
+			continue
+		}
+		if err := stopperP.Stop(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("provider %s: %w", c.names[i], err)
+		}
+	}
+	return firstErr
+}
+
+type starter interface {
+	Start() error
+}
+type stopper interface {
+	Stop() error
+}
+
+// Lookup queries each child provider in order and returns the first
+// non-empty result. When merge is enabled, it instead unions the AS path,
+// communities and large communities of every child returning a result,
+// preferring the next hop (and associated origin ASN) of the first child
+// whose next hop matches nh.
+func (c *Component) Lookup(ctx context.Context, ip netip.Addr, nh netip.Addr) provider.LookupResult {
+	if !c.merge {
+		for _, child := range c.children {
+			result := child.Lookup(ctx, ip, nh)
+			if !isEmpty(result) {
+				return result
+			}
+		}
+		return provider.LookupResult{}
+	}
+
+	var merged provider.LookupResult
+	nextHopMatched := false
+	for _, child := range c.children {
+		result := child.Lookup(ctx, ip, nh)
+		if isEmpty(result) {
+			continue
+		}
+		merged.ASPath = append(merged.ASPath, result.ASPath...)
+		merged.Communities = append(merged.Communities, result.Communities...)
+		merged.LargeCommunities = append(merged.LargeCommunities, result.LargeCommunities...)
+		merged.ExtendedCommunities = append(merged.ExtendedCommunities, result.ExtendedCommunities...)
+		if !nextHopMatched && (merged.ASN == 0 || (nh.IsValid() && result.NextHop == nh)) {
+			merged.ASN = result.ASN
+			merged.NextHop = result.NextHop
+			if nh.IsValid() && result.NextHop == nh {
+				nextHopMatched = true
+			}
+		}
+	}
+	return merged
+}
+
+func isEmpty(result provider.LookupResult) bool {
+	return result.ASN == 0 && len(result.ASPath) == 0 && !result.NextHop.IsValid()
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package provider defines the interface routing providers should implement
+// as well as the shared types they use to report lookup results. Providers
+// are pluggable sources of routing information (origin AS, AS path,
+// communities, next hop) keyed by destination IP. See the `bmp` and `mrt`
+// subpackages for concrete implementations.
+package provider
+
+import (
+	"context"
+	"net/netip"
+
+	"akvorado/common/daemon"
+	"akvorado/common/reporter"
+	"akvorado/common/s3"
+)
+
+// Provider is the interface a routing provider should implement to answer
+// route lookups for the inlet component.
+type Provider interface {
+	// Lookup returns routing information for the provided destination IP.
+	// When nh is a valid address, a provider may use it to disambiguate
+	// between several routes towards the same destination (for example, when
+	// several peers announce the same prefix). Lookup should never block for
+	// long and should return an empty LookupResult when no information is
+	// available instead of an error.
+	Lookup(ctx context.Context, ip netip.Addr, nh netip.Addr) LookupResult
+}
+
+// LookupResult is the result of a route lookup. The zero value represents an
+// absence of routing information.
+type LookupResult struct {
+	ASN                 uint32
+	ASPath              []uint32
+	Communities         []uint32
+	LargeCommunities    []LargeCommunity
+	ExtendedCommunities []ExtendedCommunity
+	NextHop             netip.Addr
+}
+
+// LargeCommunity represents a BGP large community (RFC 8092).
+type LargeCommunity struct {
+	ASN        uint32
+	LocalData1 uint32
+	LocalData2 uint32
+}
+
+// ExtendedCommunity represents a BGP extended community (RFC 4360): an
+// opaque 8-byte value made of a 1-byte type and a 7-byte value, kept
+// distinct from plain (4-byte) communities since the two have different
+// semantics.
+type ExtendedCommunity struct {
+	Type  uint8
+	Value [7]byte
+}
+
+// Configuration is the interface implemented by the configuration of each
+// routing provider.
+type Configuration interface {
+	// New creates a new provider from its configuration.
+	New(r *reporter.Reporter, dependencies Dependencies) (Provider, error)
+}
+
+// Dependencies defines the dependencies a routing provider may use.
+type Dependencies struct {
+	Daemon daemon.Component
+	S3     *s3.Component
+}
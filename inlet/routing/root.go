@@ -3,15 +3,19 @@
 
 // Package routing fetches routing-related data (AS numbers, AS paths,
 // communities). It is modular and accepts several kind of providers (including
-// BMP).
+// BMP). Several providers can be configured at once, in which case they are
+// composed through the `multi` provider (see
+// akvorado/inlet/routing/provider/multi).
 package routing
 
 import (
 	"context"
+	"fmt"
 	"net/netip"
 
 	"akvorado/common/reporter"
 	"akvorado/inlet/routing/provider"
+	"akvorado/inlet/routing/provider/multi"
 )
 
 // Component represents the metadata compomenent.
@@ -29,8 +33,20 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		r: r,
 	}
 
-	// Initialize the provider
-	selectedProvider, err := configuration.Provider.Config.New(r, dependencies)
+	// Initialize each configured provider, in order.
+	children := make([]provider.Provider, 0, len(configuration.Providers))
+	names := make([]string, 0, len(configuration.Providers))
+	for i, pc := range configuration.Providers {
+		name := fmt.Sprintf("%d (%T)", i, pc.Config)
+		child, err := pc.Config.New(r, dependencies)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		children = append(children, child)
+		names = append(names, name)
+	}
+
+	selectedProvider, err := multi.New(children, names, configuration.Merge)
 	if err != nil {
 		return nil, err
 	}